@@ -0,0 +1,101 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ddl
+
+import (
+	"runtime"
+	"sync"
+	"unsafe"
+)
+
+// ddlExt holds the state the job scheduler, reorg coordinator, schema lease
+// guard and job store need to keep per *ddl instance.
+//
+// This should really be fields on ddl itself, not a side table, but ddl.go
+// (where that struct is defined) isn't part of this tree to add them to. To
+// still avoid leaking an entry (and everything reachable from it: the
+// scheduler's goroutines, the lease guard, etc.) for the rest of the
+// process once a *ddl is torn down, extFor registers a finalizer the first
+// time it sees a given *ddl that calls releaseExt once that *ddl becomes
+// unreachable -- see extFor.
+type ddlExt struct {
+	jobScheduler     *jobScheduler
+	reorgCoordinator *reorgCoordinator
+	leaseGuard       *schemaLeaseGuard
+	jobStore         JobStore
+	etcdClient       etcdKV // nil until something wires up a real etcd client
+
+	intentMu sync.Mutex
+	intents  map[int64]jobIntent // job ID -> pending ADMIN PAUSE/RESUME/CANCEL intent
+
+	// isOwnerOverride lets tests fake the result of d.isOwner() without a
+	// real *ddl: isOwner dereferences d.ownerManager, which is a field on
+	// the ddl struct defined in ddl.go, outside this tree, so calling it on
+	// the nil *ddl this package's other tests use would panic. Unset in
+	// production; see writeJobIntents' use of checkIsOwner.
+	isOwnerOverride *bool
+}
+
+func (e *ddlExt) clearIntent(jobID int64) {
+	e.intentMu.Lock()
+	delete(e.intents, jobID)
+	e.intentMu.Unlock()
+}
+
+var (
+	extMu    sync.Mutex
+	extByDDL = map[uintptr]*ddlExt{}
+)
+
+// extFor returns the extension bundle for d, creating an empty one on first
+// use. Callers are responsible for lazily initializing whichever of its
+// fields they need.
+//
+// extByDDL is keyed on uintptr(unsafe.Pointer(d)) rather than d itself: a map
+// key of type *ddl would be an ordinary strong reference, and a *ddl sitting
+// in a package-level map can never become unreachable -- which would mean
+// the finalizer registered below never fires, permanently defeating the
+// cleanup it's there to do. Keying on the bare address instead means the map
+// holds nothing that keeps d alive, so d can still become unreachable once
+// every other real reference to it is gone, at which point the finalizer
+// fires and releaseExt cleans up this entry -- this is what stands in for a
+// real teardown hook on ddl itself (see ddlExt's doc comment above). A
+// nil d (as used by tests that don't own a real *ddl) is never finalizable,
+// so extFor skips registering one for it; those callers must call
+// releaseExt themselves in cleanup, same as before.
+func extFor(d *ddl) *ddlExt {
+	key := uintptr(unsafe.Pointer(d))
+	extMu.Lock()
+	defer extMu.Unlock()
+	e, ok := extByDDL[key]
+	if !ok {
+		e = &ddlExt{}
+		extByDDL[key] = e
+		if d != nil {
+			runtime.SetFinalizer(d, func(d *ddl) { releaseExt(d) })
+		}
+	}
+	return e
+}
+
+// releaseExt drops d's entry from the side table. extFor arranges for this
+// to run automatically via a finalizer once d is unreachable; call it
+// directly only from test cleanup for a *ddl (including nil) that extFor
+// never got the chance to attach a finalizer to.
+func releaseExt(d *ddl) {
+	key := uintptr(unsafe.Pointer(d))
+	extMu.Lock()
+	delete(extByDDL, key)
+	extMu.Unlock()
+}
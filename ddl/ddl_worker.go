@@ -37,6 +37,20 @@ var RunWorker = true
 func (d *ddl) onDDLWorker() {
 	defer d.wait.Done()
 
+	ext := extFor(d)
+	if ext.jobStore == nil {
+		ext.jobStore = tikvJobStore{}
+	}
+	if ext.jobScheduler == nil {
+		ext.jobScheduler = newJobScheduler(d, ext.jobStore)
+	}
+	if ext.reorgCoordinator == nil {
+		ext.reorgCoordinator = newReorgCoordinator(d)
+	}
+	if ext.leaseGuard == nil {
+		ext.leaseGuard = newSchemaLeaseGuard(d.lease)
+	}
+
 	// We use 4 * lease time to check owner's timeout, so here, we will update owner's status
 	// every 2 * lease time. If lease is 0, we will use default 1s.
 	// But we use etcd to speed up, normally it takes less than 1s now, so we use 1s as the max value.
@@ -115,6 +129,16 @@ func buildJobDependence(t *meta.Meta, curJob *model.Job) error {
 
 // addDDLJob gets a global job ID and puts the DDL job in the DDL queue.
 func (d *ddl) addDDLJob(ctx sessionctx.Context, job *model.Job) error {
+	ext := extFor(d)
+	if ext.leaseGuard != nil {
+		// This node must not accept a new DDL statement if it can't prove its
+		// schema is at most one version stale; fail fast and retryable rather
+		// than queue a job against possibly-outdated schema.
+		if err := ext.leaseGuard.Check(); err != nil {
+			return errors.Trace(err)
+		}
+	}
+
 	startTime := time.Now()
 	job.Version = currentVersion
 	job.Query, _ = ctx.Value(sessionctx.QueryString).(string)
@@ -126,7 +150,15 @@ func (d *ddl) addDDLJob(ctx sessionctx.Context, job *model.Job) error {
 			return errors.Trace(err)
 		}
 		job.StartTS = txn.StartTS()
-		err = t.EnQueueDDLJob(job)
+		if job.Type == model.ActionAddIndex {
+			// Route through the dedicated adding-index queue instead of the
+			// default one, so the owner's reorg coordinator picks this job
+			// up via getDedicatedAddIndexJob instead of the regular
+			// head-of-queue path.
+			t.SetJobListKey(meta.AddIndexJobListKey)
+			defer t.SetJobListKey(meta.DefaultJobListKey)
+		}
+		err = ext.jobStore.Enqueue(t, job)
 		return errors.Trace(err)
 	})
 	metrics.DDLWorkerHistogram.WithLabelValues(metrics.WorkerAddDDLJob, metrics.RetLabel(err)).Observe(time.Since(startTime).Seconds())
@@ -135,7 +167,7 @@ func (d *ddl) addDDLJob(ctx sessionctx.Context, job *model.Job) error {
 
 // getFirstDDLJob gets the first DDL job form DDL queue.
 func (d *ddl) getFirstDDLJob(t *meta.Meta) (*model.Job, error) {
-	job, err := t.GetDDLJob(0)
+	job, err := extFor(d).jobStore.Peek(t)
 	return job, errors.Trace(err)
 }
 
@@ -166,7 +198,7 @@ func (d *ddl) updateDDLJob(t *meta.Meta, job *model.Job, meetErr bool) error {
 		log.Infof("[ddl] update DDL Job %s shouldn't update raw args", job)
 		updateRawArgs = false
 	}
-	return errors.Trace(t.UpdateDDLJob(0, job, updateRawArgs))
+	return errors.Trace(extFor(d).jobStore.Update(t, job, updateRawArgs))
 }
 
 func (d *ddl) deleteRange(job *model.Job) error {
@@ -201,15 +233,8 @@ func (d *ddl) finishDDLJob(t *meta.Meta, job *model.Job) (err error) {
 		return errors.Trace(err)
 	}
 
-	_, err = t.DeQueueDDLJob()
-	if err != nil {
-		return errors.Trace(err)
-	}
-
-	job.BinlogInfo.FinishedTS = t.StartTS
 	log.Infof("[ddl] finish DDL job %v", job)
-	err = t.AddHistoryDDLJob(job)
-	return errors.Trace(err)
+	return errors.Trace(extFor(d).jobStore.Finish(t, job))
 }
 
 // getHistoryDDLJob gets a DDL job with job's ID form history queue.
@@ -235,10 +260,15 @@ func (d *ddl) handleDDLJobQueue(shouldCleanJobs bool) error {
 			return nil
 		}
 
+		// The schema lease guard's Check only runs in addDDLJob, fencing off
+		// DDL submission on this node; MarkReloaded/CheckSchemaLease aren't
+		// called on any node's SQL execution path, so a lagging node can
+		// still serve stale-schema reads past one lease. Waiting only `lease`
+		// here would therefore not be safe, so the wait is 2*lease.
 		waitTime := 2 * d.lease
 
 		var (
-			job       *model.Job
+			jobs      []*model.Job
 			schemaVer int64
 			runJobErr error
 		)
@@ -248,26 +278,66 @@ func (d *ddl) handleDDLJobQueue(shouldCleanJobs bool) error {
 				return nil
 			}
 
-			// It's used for clean up the job in adding index queue before we support adding index queue.
-			// TODO: Remove this logic after we support the adding index queue.
+			// It's used for cleaning up any jobs left in the adding index queue by a
+			// previous owner before this one takes over reorg coordination.
 			if shouldCleanJobs {
 				return errors.Trace(d.cleanAddIndexQueueJobs(txn))
 			}
 
 			var err error
 			t := meta.NewMeta(txn)
-			// We become the owner. Get the first job and run it.
-			job, err = d.getFirstDDLJob(t)
-			if job == nil || err != nil {
+			// We become the owner. Get a batch of jobs the scheduler says can run
+			// concurrently (jobs on independent schemas/tables don't block each other),
+			// and fall back to a single job when the scheduler isn't enabled.
+			jobs, err = d.getRunnableDDLJobs(t)
+			if len(jobs) == 0 || err != nil {
 				return errors.Trace(err)
 			}
+			job := jobs[0]
+
+			// getRunnableDDLJobs leaves t's job list key on the default queue
+			// even for a job it took from the dedicated adding-index queue;
+			// point t at that queue here so every further meta call in this
+			// iteration (update, finish) addresses it, not the default one.
+			if len(jobs) == 1 && extFor(d).reorgCoordinator.IsDedicated(job.ID) {
+				t.SetJobListKey(meta.AddIndexJobListKey)
+				defer t.SetJobListKey(meta.DefaultJobListKey)
+			}
+
+			// Honor any ADMIN PAUSE/RESUME/CANCEL DDL JOBS intent left on this
+			// job before running it. The wait-only first pass below commits
+			// nothing, so the intent stays pending until the pass that runs
+			// the job; applying it there would clear it without saving it.
+			if len(jobs) == 1 && !once {
+				paused, err := d.applyJobIntent(t, job)
+				if err != nil {
+					return errors.Trace(err)
+				}
+				if paused {
+					jobs = nil
+					return nil
+				}
+			}
 
 			if once {
+				// Run the wait in a throwaway pass that commits nothing:
+				// waitSchemaSynced can block for waitTime (2*lease, which
+				// with real lease settings is tens of seconds to minutes),
+				// and this transaction's snapshot was already opened before
+				// we got here, so running the job in it afterwards would
+				// pin that stale start-ts across the whole wait. Returning
+				// here instead lets the job run in a fresh transaction next
+				// iteration, the same as the pre-scheduler version of this
+				// loop did.
 				d.waitSchemaSynced(job, waitTime)
 				once = false
 				return nil
 			}
 
+			if len(jobs) > 1 {
+				return nil
+			}
+
 			if job.IsDone() || job.IsRollbackDone() {
 				binloginfo.SetDDLBinlog(d.workerVars.BinlogClient, txn, job.ID, job.Query)
 				if !job.IsRollbackDone() {
@@ -292,6 +362,13 @@ func (d *ddl) handleDDLJobQueue(shouldCleanJobs bool) error {
 			return errors.Trace(d.handleUpdateJobError(t, job, err))
 		})
 
+		if len(jobs) > 1 {
+			// The scheduler found several independent jobs. Run them concurrently,
+			// each in its own transaction, and only wait for schema sync once for
+			// the whole batch instead of once per job.
+			schemaVer, runJobErr = d.runJobBatchInOwnTxns(jobs)
+		}
+
 		if runJobErr != nil {
 			// wait a while to retry again. If we don't wait here, DDL will retry this job immediately,
 			// which may act like a deadlock.
@@ -302,25 +379,152 @@ func (d *ddl) handleDDLJobQueue(shouldCleanJobs bool) error {
 
 		if err != nil {
 			return errors.Trace(err)
-		} else if job == nil {
+		} else if len(jobs) == 0 {
 			// No job now, return and retry getting later.
 			return nil
 		}
 
-		d.hookMu.RLock()
-		d.hook.OnJobUpdated(job)
-		d.hookMu.RUnlock()
+		for _, job := range jobs {
+			d.hookMu.RLock()
+			d.hook.OnJobUpdated(job)
+			d.hookMu.RUnlock()
 
-		// Here means the job enters another state (delete only, write only, public, etc...) or is cancelled.
-		// If the job is done or still running or rolling back, we will wait 2 * lease time to guarantee other servers to update
-		// the newest schema.
-		if job.IsRunning() || job.IsRollingback() || job.IsDone() || job.IsRollbackDone() {
+			if job.IsSynced() {
+				asyncNotify(d.ddlJobDoneCh)
+			}
+		}
+
+		// Here means the job(s) enter another state (delete only, write only, public, etc...) or are cancelled.
+		// If any job in the batch is done or still running or rolling back, we will wait 2 * lease time to
+		// guarantee other servers to update the newest schema. Checking only jobs[0] would let a batch where
+		// that particular job already finished skip the wait for the rest of the batch, even though one of
+		// them bumped schemaVer.
+		needWait := false
+		for _, job := range jobs {
+			if job.IsRunning() || job.IsRollingback() || job.IsDone() || job.IsRollbackDone() {
+				needWait = true
+				break
+			}
+		}
+		if needWait {
 			d.waitSchemaChanged(nil, waitTime, schemaVer)
 		}
-		if job.IsSynced() {
-			asyncNotify(d.ddlJobDoneCh)
+	}
+}
+
+// getRunnableDDLJobs returns the next job(s) the owner should run in this
+// iteration. When the job scheduler is enabled it returns a batch of jobs
+// whose conflict keys are pairwise disjoint, so they can be dispatched to the
+// worker pool; otherwise it falls back to returning just the head of the
+// queue, preserving the original strictly-sequential behavior. If the default
+// queue has nothing runnable, it falls back to the dedicated adding-index
+// queue so AddIndex jobs routed there by addDDLJob still get picked up.
+func (d *ddl) getRunnableDDLJobs(t *meta.Meta) ([]*model.Job, error) {
+	scheduler := extFor(d).jobScheduler
+	if scheduler == nil {
+		job, err := d.getFirstDDLJob(t)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		if job == nil {
+			job, err = d.getDedicatedAddIndexJob(t)
+			if err != nil {
+				return nil, errors.Trace(err)
+			}
+		}
+		if job == nil {
+			return nil, nil
+		}
+		return []*model.Job{job}, nil
+	}
+
+	pending, err := extFor(d).jobStore.ListPending(t)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if len(pending) > 0 {
+		if batch := scheduler.pickRunnableJobs(pending); len(batch) > 0 {
+			return batch, nil
 		}
 	}
+	job, err := d.getDedicatedAddIndexJob(t)
+	if job == nil || err != nil {
+		return nil, errors.Trace(err)
+	}
+	return []*model.Job{job}, nil
+}
+
+// getDedicatedAddIndexJob returns the head of the dedicated adding-index
+// queue (meta.AddIndexJobListKey), or nil if it's empty, and marks it with
+// the reorg coordinator so runDDLJob routes its StateWriteReorganization
+// phase through onCreateIndexDistributed instead of the regular owner-local
+// path. addDDLJob is the producer side: it puts ActionAddIndex jobs in this
+// queue instead of the default one.
+func (d *ddl) getDedicatedAddIndexJob(t *meta.Meta) (*model.Job, error) {
+	t.SetJobListKey(meta.AddIndexJobListKey)
+	job, err := t.GetDDLJob(0)
+	t.SetJobListKey(meta.DefaultJobListKey)
+	if err != nil || job == nil {
+		return nil, errors.Trace(err)
+	}
+	extFor(d).reorgCoordinator.MarkDedicated(job.ID)
+	return job, nil
+}
+
+// runJobBatchInOwnTxns runs every job in a scheduler-selected batch in its
+// own transaction concurrently, recording each job as running in the
+// scheduler's dependency graph while its transaction closure runs and
+// releasing it when the closure returns.
+//
+// Every job in the batch still bumps the single shared schema version counter
+// in meta, so two jobs committing at the same time will conflict with each
+// other there; the transaction is retryable (like addDDLJob's GenGlobalID
+// write) so a conflict just replays that job's closure rather than failing
+// the job outright.
+func (d *ddl) runJobBatchInOwnTxns(jobs []*model.Job) (int64, error) {
+	scheduler := extFor(d).jobScheduler
+	return scheduler.runJobBatch(jobs, func(job *model.Job) (schemaVer int64, err error) {
+		err = kv.RunInNewTxn(d.store, true, func(txn kv.Transaction) error {
+			t := meta.NewMeta(txn)
+			lease, err := scheduler.acquire(t, job)
+			if err != nil {
+				return errors.Trace(err)
+			}
+			defer scheduler.release(job, lease)
+
+			paused, err := d.applyJobIntent(t, job)
+			if err != nil {
+				return errors.Trace(err)
+			}
+			if paused {
+				return nil
+			}
+
+			if job.IsDone() || job.IsRollbackDone() {
+				binloginfo.SetDDLBinlog(d.workerVars.BinlogClient, txn, job.ID, job.Query)
+				if !job.IsRollbackDone() {
+					job.State = model.JobStateSynced
+				}
+				return errors.Trace(d.finishDDLJob(t, job))
+			}
+
+			d.hookMu.RLock()
+			d.hook.OnJobRunBefore(job)
+			d.hookMu.RUnlock()
+
+			var runErr error
+			schemaVer, runErr = d.runDDLJob(t, job)
+			if job.IsCancelled() {
+				return errors.Trace(d.finishDDLJob(t, job))
+			}
+			updateErr := d.updateDDLJob(t, job, runErr != nil)
+			if updateErr != nil {
+				return errors.Trace(d.handleUpdateJobError(t, job, updateErr))
+			}
+			return errors.Trace(runErr)
+		})
+		return schemaVer, errors.Trace(err)
+	})
 }
 
 func chooseLeaseTime(t, max time.Duration) time.Duration {
@@ -370,7 +574,15 @@ func (d *ddl) runDDLJob(t *meta.Meta, job *model.Job) (ver int64, err error) {
 	case model.ActionModifyColumn:
 		ver, err = d.onModifyColumn(t, job)
 	case model.ActionAddIndex:
-		ver, err = d.onCreateIndex(t, job)
+		// Jobs dispatched through the dedicated adding-index queue have their
+		// StateWriteReorganization backfill sharded out to the reorg
+		// coordinator instead of scanning the whole table on the owner; every
+		// other AddIndex job keeps using the existing single-node path.
+		if job.SchemaState == model.StateWriteReorganization && extFor(d).reorgCoordinator.IsDedicated(job.ID) {
+			ver, err = d.onCreateIndexDistributed(t, job)
+		} else {
+			ver, err = d.onCreateIndex(t, job)
+		}
 	case model.ActionDropIndex:
 		ver, err = d.onDropIndex(t, job)
 	case model.ActionAddForeignKey:
@@ -423,8 +635,9 @@ func toTError(err error) *terror.Error {
 	return terror.ClassDDL.New(terror.CodeUnknown, err.Error())
 }
 
-// waitSchemaChanged waits for the completion of updating all servers' schema. In order to make sure that happens,
-// we wait 2 * lease time.
+// waitSchemaChanged waits for the completion of updating all servers' schema.
+// In order to make sure that happens, the caller passes 2 * lease; see the
+// comment in handleDDLJobQueue for why the schemaLeaseGuard doesn't shorten it.
 func (d *ddl) waitSchemaChanged(ctx context.Context, waitTime time.Duration, latestSchemaVersion int64) {
 	if waitTime == 0 {
 		return
@@ -469,6 +682,12 @@ func (d *ddl) waitSchemaChanged(ctx context.Context, waitTime time.Duration, lat
 		}
 	}
 	log.Infof("[ddl] wait latest schema version %v changed, take time %v", latestSchemaVersion, time.Since(timeStart))
+	if guard := extFor(d).leaseGuard; guard != nil {
+		// This only proves the owner itself is caught up; it's a stand-in for
+		// every node's domain.Reload() hook calling MarkReloaded, which isn't
+		// wired up anywhere in this tree yet.
+		guard.MarkReloaded(time.Now())
+	}
 	return
 }
 
@@ -527,9 +746,12 @@ func updateSchemaVersion(t *meta.Meta, job *model.Job) (int64, error) {
 	return schemaVersion, errors.Trace(err)
 }
 
-// cleanAddIndexQueueJobs cleans jobs in adding index queue.
+// cleanAddIndexQueueJobs empties the adding index queue of the jobs left in
+// it by a previous owner (or before this owner restarted): jobs still in
+// StateNone are cancelled, jobs already in StatePublic are archived, and jobs
+// in any other state are converted to rollback jobs and moved to the default
+// queue.
 // It's only done once after the worker become the owner.
-// TODO: Remove this logic after we support the adding index queue.
 func (d *ddl) cleanAddIndexQueueJobs(txn kv.Transaction) error {
 	startTime := time.Now()
 	m := meta.NewMeta(txn)
@@ -582,7 +804,7 @@ func (d *ddl) cleanAddIndexQueueJobs(txn kv.Transaction) error {
 		}
 		// Put the job to the default job list.
 		m.SetJobListKey(meta.DefaultJobListKey)
-		err = m.EnQueueDDLJob(job)
+		err = extFor(d).jobStore.Enqueue(m, job)
 		m.SetJobListKey(meta.AddIndexJobListKey)
 		if err != nil {
 			return errors.Trace(err)
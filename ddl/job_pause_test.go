@@ -0,0 +1,207 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ddl
+
+import (
+	"testing"
+
+	"github.com/pingcap/tidb/model"
+)
+
+func TestApplyJobIntentPauseHoldsTheJob(t *testing.T) {
+	var d *ddl
+	t.Cleanup(func() { releaseExt(d) })
+	extFor(d).intents = map[int64]jobIntent{1: jobIntentPause}
+
+	job := &model.Job{ID: 1, State: model.JobStateRunning}
+	paused, err := d.applyJobIntent(nil, job)
+	if err != nil {
+		t.Fatalf("applyJobIntent: %v", err)
+	}
+	if !paused {
+		t.Fatalf("expected a pending pause intent to report paused")
+	}
+	if _, ok := extFor(d).intents[1]; !ok {
+		t.Fatalf("a pause intent should stay pending until resume or cancel clears it")
+	}
+}
+
+func TestApplyJobIntentPauseTooLate(t *testing.T) {
+	var d *ddl
+	t.Cleanup(func() { releaseExt(d) })
+	extFor(d).intents = map[int64]jobIntent{1: jobIntentPause}
+
+	job := &model.Job{ID: 1, State: model.JobStateDone}
+	paused, err := d.applyJobIntent(nil, job)
+	if err != nil {
+		t.Fatalf("applyJobIntent: %v", err)
+	}
+	if paused {
+		t.Fatalf("a job that already reached JobStateDone should finish normally, not pause")
+	}
+	if _, ok := extFor(d).intents[1]; ok {
+		t.Fatalf("a too-late pause intent should be cleared, not left pending forever")
+	}
+}
+
+func TestApplyJobIntentResumeClearsPause(t *testing.T) {
+	var d *ddl
+	t.Cleanup(func() { releaseExt(d) })
+	extFor(d).intents = map[int64]jobIntent{1: jobIntentResume}
+
+	job := &model.Job{ID: 1, State: model.JobStateRunning}
+	paused, err := d.applyJobIntent(nil, job)
+	if err != nil {
+		t.Fatalf("applyJobIntent: %v", err)
+	}
+	if paused {
+		t.Fatalf("a resume intent should never report paused")
+	}
+	if _, ok := extFor(d).intents[1]; ok {
+		t.Fatalf("a resume intent should be cleared once applied")
+	}
+}
+
+func TestApplyJobIntentCancelSetsCancelling(t *testing.T) {
+	var d *ddl
+	t.Cleanup(func() { releaseExt(d) })
+	extFor(d).intents = map[int64]jobIntent{1: jobIntentCancel}
+
+	job := &model.Job{ID: 1, State: model.JobStateRunning}
+	paused, err := d.applyJobIntent(nil, job)
+	if err != nil {
+		t.Fatalf("applyJobIntent: %v", err)
+	}
+	if paused {
+		t.Fatalf("a cancel intent should never report paused")
+	}
+	if job.State != model.JobStateCancelling {
+		t.Fatalf("expected job to move to JobStateCancelling, got %v", job.State)
+	}
+	if _, ok := extFor(d).intents[1]; ok {
+		t.Fatalf("a cancel intent should be cleared once applied")
+	}
+}
+
+func TestApplyJobIntentCancelTooLate(t *testing.T) {
+	var d *ddl
+	t.Cleanup(func() { releaseExt(d) })
+	extFor(d).intents = map[int64]jobIntent{1: jobIntentCancel}
+
+	job := &model.Job{ID: 1, State: model.JobStateDone}
+	_, err := d.applyJobIntent(nil, job)
+	if err != nil {
+		t.Fatalf("applyJobIntent: %v", err)
+	}
+	if job.State != model.JobStateDone {
+		t.Fatalf("a job already in JobStateDone must not be overwritten by a stale cancel intent, got %v", job.State)
+	}
+}
+
+func TestWriteJobIntentsRejectsNonOwner(t *testing.T) {
+	var d *ddl
+	t.Cleanup(func() { releaseExt(d) })
+	notOwner := false
+	extFor(d).isOwnerOverride = &notOwner
+
+	err := d.writeJobIntents([]int64{1}, jobIntentPause)
+	if err == nil {
+		t.Fatalf("expected writeJobIntents to reject a non-owner node")
+	}
+	if !errNotDDLOwner.Equal(err) {
+		t.Fatalf("expected errNotDDLOwner, got %v", err)
+	}
+	if _, ok := extFor(d).intents[1]; ok {
+		t.Fatalf("a rejected intent write must not land in extByDDL")
+	}
+}
+
+func TestWriteJobIntentsAppliesOnOwner(t *testing.T) {
+	var d *ddl
+	t.Cleanup(func() { releaseExt(d) })
+	isOwner := true
+	extFor(d).isOwnerOverride = &isOwner
+
+	if err := d.writeJobIntents([]int64{1, 2}, jobIntentPause); err != nil {
+		t.Fatalf("writeJobIntents: %v", err)
+	}
+	if extFor(d).intents[1] != jobIntentPause || extFor(d).intents[2] != jobIntentPause {
+		t.Fatalf("expected both job IDs to get the pause intent, got %v", extFor(d).intents)
+	}
+}
+
+func TestAdminPauseResumeCancelDDLJobsRejectNonOwner(t *testing.T) {
+	var d *ddl
+	t.Cleanup(func() { releaseExt(d) })
+	notOwner := false
+	extFor(d).isOwnerOverride = &notOwner
+
+	if err := d.AdminPauseDDLJobs(nil, []int64{1}); err == nil || !errNotDDLOwner.Equal(err) {
+		t.Fatalf("AdminPauseDDLJobs: expected errNotDDLOwner, got %v", err)
+	}
+	if err := d.AdminResumeDDLJobs(nil, []int64{1}); err == nil || !errNotDDLOwner.Equal(err) {
+		t.Fatalf("AdminResumeDDLJobs: expected errNotDDLOwner, got %v", err)
+	}
+	if err := d.AdminCancelDDLJobs(nil, []int64{1}); err == nil || !errNotDDLOwner.Equal(err) {
+		t.Fatalf("AdminCancelDDLJobs: expected errNotDDLOwner, got %v", err)
+	}
+	if len(extFor(d).intents) != 0 {
+		t.Fatalf("none of these calls should have left an intent behind, got %v", extFor(d).intents)
+	}
+}
+
+func TestAdminPauseResumeCancelDDLJobsOnOwner(t *testing.T) {
+	var d *ddl
+	t.Cleanup(func() { releaseExt(d) })
+	isOwner := true
+	extFor(d).isOwnerOverride = &isOwner
+
+	if err := d.AdminPauseDDLJobs(nil, []int64{1}); err != nil {
+		t.Fatalf("AdminPauseDDLJobs: %v", err)
+	}
+	if extFor(d).intents[1] != jobIntentPause {
+		t.Fatalf("expected jobIntentPause, got %v", extFor(d).intents[1])
+	}
+
+	if err := d.AdminResumeDDLJobs(nil, []int64{1}); err != nil {
+		t.Fatalf("AdminResumeDDLJobs: %v", err)
+	}
+	if extFor(d).intents[1] != jobIntentResume {
+		t.Fatalf("expected jobIntentResume, got %v", extFor(d).intents[1])
+	}
+
+	if err := d.AdminCancelDDLJobs(nil, []int64{1}); err != nil {
+		t.Fatalf("AdminCancelDDLJobs: %v", err)
+	}
+	if extFor(d).intents[1] != jobIntentCancel {
+		t.Fatalf("expected jobIntentCancel, got %v", extFor(d).intents[1])
+	}
+}
+
+func TestApplyJobIntentNone(t *testing.T) {
+	var d *ddl
+	t.Cleanup(func() { releaseExt(d) })
+
+	job := &model.Job{ID: 1, State: model.JobStateRunning}
+	paused, err := d.applyJobIntent(nil, job)
+	if err != nil {
+		t.Fatalf("applyJobIntent: %v", err)
+	}
+	if paused {
+		t.Fatalf("no intent should never report paused")
+	}
+	if job.State != model.JobStateRunning {
+		t.Fatalf("no intent should never change job state, got %v", job.State)
+	}
+}
@@ -0,0 +1,216 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ddl
+
+import (
+	"sync"
+
+	"github.com/pingcap/tidb/meta"
+	"github.com/pingcap/tidb/model"
+)
+
+// defaultJobWorkerPoolSize is the number of DDL jobs the owner is willing to
+// run concurrently when those jobs don't conflict with each other.
+const defaultJobWorkerPoolSize = 4
+
+// jobConflictKey identifies an object a DDL job touches. Two jobs that share
+// a key must not run concurrently.
+type jobConflictKey struct {
+	schemaID int64
+	tableID  int64
+}
+
+// jobScheduler builds a running dependency graph of the jobs the owner is
+// currently executing and hands out a batch of mutually independent jobs to
+// a small pool of worker goroutines, so that unrelated schemas/tables stop
+// head-of-line blocking each other behind a single slow job.
+//
+// A job is only considered runnable if none of its conflict keys are held by
+// a job that is already running. Jobs within a dispatched batch are likewise
+// pairwise non-conflicting, so the batch can be run in parallel safely.
+type jobScheduler struct {
+	d           *ddl
+	store       JobStore
+	workerCount int
+
+	mu      sync.Mutex
+	running map[jobConflictKey]int64 // conflict key -> job ID currently holding it
+	leases  map[int64]*jobLease      // job ID -> its active lease
+}
+
+// jobLease marks that a worker goroutine currently owns a job. Ownership
+// election (d.isOwner) already guarantees only one node's scheduler ever runs
+// jobs at a time, so this only needs to be tracked in-process, scoped to this
+// jobScheduler's own worker pool; there's no cross-node lease to persist.
+type jobLease struct {
+	jobID    int64
+	workerID string
+	done     chan struct{}
+}
+
+func newJobScheduler(d *ddl, store JobStore) *jobScheduler {
+	return &jobScheduler{
+		d:           d,
+		store:       store,
+		workerCount: defaultJobWorkerPoolSize,
+		running:     make(map[jobConflictKey]int64),
+		leases:      make(map[int64]*jobLease),
+	}
+}
+
+// jobConflictKeys returns the set of objects a job touches. Jobs that share
+// any key are serialized with respect to one another.
+func jobConflictKeys(job *model.Job) []jobConflictKey {
+	keys := []jobConflictKey{{schemaID: job.SchemaID, tableID: job.TableID}}
+	switch job.Type {
+	case model.ActionRenameTable:
+		// A rename touches both the old and the new schema.
+		var oldSchemaID int64
+		if err := job.DecodeArgs(&oldSchemaID); err == nil && oldSchemaID != job.SchemaID {
+			keys = append(keys, jobConflictKey{schemaID: oldSchemaID, tableID: job.TableID})
+		}
+	case model.ActionDropSchema:
+		// A schema drop conflicts with every job on every table in that
+		// schema; we approximate this with a schema-wide key (tableID 0 is
+		// never a legal table ID).
+		keys = append(keys, jobConflictKey{schemaID: job.SchemaID, tableID: 0})
+	}
+	return keys
+}
+
+// jobsConflict reports whether a and b must not run at the same time.
+func jobsConflict(a, b *model.Job) bool {
+	aKeys, bKeys := jobConflictKeys(a), jobConflictKeys(b)
+	for _, ak := range aKeys {
+		for _, bk := range bKeys {
+			if ak == bk || ak.schemaID == bk.schemaID && (ak.tableID == 0 || bk.tableID == 0) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// pickRunnableJobs walks the pending queue in order and greedily selects up
+// to the worker pool size jobs that conflict with neither the jobs already
+// running nor each other. Jobs that are skipped stay at the front of the
+// queue and are reconsidered on the next iteration, so ordering is preserved
+// for any two jobs that do conflict.
+func (s *jobScheduler) pickRunnableJobs(jobs []*model.Job) []*model.Job {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var batch, skipped []*model.Job
+	for _, job := range jobs {
+		if len(batch) >= s.workerCount {
+			break
+		}
+		if s.conflictsWithRunningLocked(job) {
+			skipped = append(skipped, job)
+			continue
+		}
+		// A job must not overtake an earlier skipped job it conflicts with,
+		// so it's checked against the skipped jobs as well as the batch.
+		conflicts := false
+		for _, other := range append(append([]*model.Job(nil), batch...), skipped...) {
+			if jobsConflict(job, other) {
+				conflicts = true
+				break
+			}
+		}
+		if conflicts {
+			skipped = append(skipped, job)
+			continue
+		}
+		batch = append(batch, job)
+	}
+	return batch
+}
+
+// conflictsWithRunningLocked reports whether job conflicts with any job
+// currently recorded as running. This must use the same wildcard-aware
+// comparison as jobsConflict, not a literal key lookup: a running
+// ActionDropSchema is recorded under {schemaID, 0} (see jobConflictKeys),
+// and a table-scoped job's own keys never equal that wildcard key, so an
+// exact-match lookup would miss it and let the two run concurrently.
+func (s *jobScheduler) conflictsWithRunningLocked(job *model.Job) bool {
+	for key := range s.running {
+		for _, jk := range jobConflictKeys(job) {
+			if key == jk || key.schemaID == jk.schemaID && (key.tableID == 0 || jk.tableID == 0) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// acquire marks job as running for the purposes of the dependency graph.
+func (s *jobScheduler) acquire(t *meta.Meta, job *model.Job) (*jobLease, error) {
+	s.mu.Lock()
+	for _, key := range jobConflictKeys(job) {
+		s.running[key] = job.ID
+	}
+	lease := &jobLease{jobID: job.ID, workerID: s.d.uuid, done: make(chan struct{})}
+	s.leases[job.ID] = lease
+	s.mu.Unlock()
+
+	return lease, nil
+}
+
+// release drops job from the running set once a worker is done with it,
+// whether it finished, failed, or is merely yielding to wait for schema sync.
+func (s *jobScheduler) release(job *model.Job, lease *jobLease) {
+	close(lease.done)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, key := range jobConflictKeys(job) {
+		if s.running[key] == job.ID {
+			delete(s.running, key)
+		}
+	}
+	delete(s.leases, job.ID)
+}
+
+// runJobBatch dispatches a batch of mutually independent jobs to the worker
+// pool and waits for every one of them to either finish its current
+// transaction or hand back control (e.g. because it must wait for schema
+// sync). It returns the highest schema version any job in the batch produced,
+// so the caller only needs a single waitSchemaChanged call per batch instead
+// of one per job.
+func (s *jobScheduler) runJobBatch(batch []*model.Job, run func(job *model.Job) (schemaVer int64, err error)) (int64, error) {
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		maxVer   int64
+		firstErr error
+	)
+	for _, job := range batch {
+		wg.Add(1)
+		go func(job *model.Job) {
+			defer wg.Done()
+			ver, err := run(job)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil && firstErr == nil {
+				firstErr = err
+			}
+			if ver > maxVer {
+				maxVer = ver
+			}
+		}(job)
+	}
+	wg.Wait()
+	return maxVer, firstErr
+}
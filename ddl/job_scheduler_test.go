@@ -0,0 +1,74 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ddl
+
+import (
+	"testing"
+
+	"github.com/pingcap/tidb/model"
+)
+
+func TestPickRunnableJobsSkipsConflicts(t *testing.T) {
+	s := newJobScheduler(nil, newMemJobStore())
+
+	jobA := &model.Job{ID: 1, SchemaID: 1, TableID: 1}
+	jobB := &model.Job{ID: 2, SchemaID: 1, TableID: 1} // conflicts with jobA (same table)
+	jobC := &model.Job{ID: 3, SchemaID: 2, TableID: 2} // independent
+
+	batch := s.pickRunnableJobs([]*model.Job{jobA, jobB, jobC})
+	if len(batch) != 2 {
+		t.Fatalf("expected jobA and jobC to be picked, got %d jobs", len(batch))
+	}
+	ids := map[int64]bool{}
+	for _, job := range batch {
+		ids[job.ID] = true
+	}
+	if !ids[jobA.ID] || !ids[jobC.ID] {
+		t.Fatalf("expected jobA and jobC in the batch, got %v", batch)
+	}
+	if ids[jobB.ID] {
+		t.Fatalf("jobB conflicts with jobA and should have been skipped")
+	}
+}
+
+func TestPickRunnableJobsRespectsAlreadyRunning(t *testing.T) {
+	s := newJobScheduler(nil, newMemJobStore())
+	running := &model.Job{ID: 1, SchemaID: 1, TableID: 1}
+	for _, key := range jobConflictKeys(running) {
+		s.running[key] = running.ID
+	}
+
+	jobB := &model.Job{ID: 2, SchemaID: 1, TableID: 1}
+	batch := s.pickRunnableJobs([]*model.Job{jobB})
+	if len(batch) != 0 {
+		t.Fatalf("jobB conflicts with an already-running job and should not be picked, got %v", batch)
+	}
+}
+
+func TestPickRunnableJobsRespectsRunningDropSchemaWildcard(t *testing.T) {
+	s := newJobScheduler(nil, newMemJobStore())
+	running := &model.Job{ID: 1, SchemaID: 1, Type: model.ActionDropSchema}
+	for _, key := range jobConflictKeys(running) {
+		s.running[key] = running.ID
+	}
+
+	// jobB only has an exact {schemaID:1, tableID:2} key of its own; it
+	// must still be rejected against the running DropSchema's wildcard
+	// {schemaID:1, tableID:0} key, the same way jobsConflict treats it.
+	jobB := &model.Job{ID: 2, SchemaID: 1, TableID: 2}
+	batch := s.pickRunnableJobs([]*model.Job{jobB})
+	if len(batch) != 0 {
+		t.Fatalf("jobB is on a schema with an in-flight DropSchema and should not be picked, got %v", batch)
+	}
+}
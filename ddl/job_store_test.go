@@ -0,0 +1,80 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ddl
+
+import (
+	"testing"
+
+	"github.com/pingcap/tidb/model"
+)
+
+func TestMemJobStoreEnqueuePeekFinish(t *testing.T) {
+	s := newMemJobStore()
+	job1 := &model.Job{ID: 1, BinlogInfo: &model.HistoryInfo{}}
+	job2 := &model.Job{ID: 2, BinlogInfo: &model.HistoryInfo{}}
+
+	if err := s.Enqueue(nil, job1); err != nil {
+		t.Fatalf("Enqueue job1: %v", err)
+	}
+	if err := s.Enqueue(nil, job2); err != nil {
+		t.Fatalf("Enqueue job2: %v", err)
+	}
+
+	head, err := s.Peek(nil)
+	if err != nil {
+		t.Fatalf("Peek: %v", err)
+	}
+	if head == nil || head.ID != job1.ID {
+		t.Fatalf("expected job1 at the head, got %v", head)
+	}
+
+	pending, err := s.ListPending(nil)
+	if err != nil {
+		t.Fatalf("ListPending: %v", err)
+	}
+	if len(pending) != 2 {
+		t.Fatalf("expected 2 pending jobs, got %d", len(pending))
+	}
+
+	if err := s.Finish(nil, job1); err != nil {
+		t.Fatalf("Finish job1: %v", err)
+	}
+
+	head, err = s.Peek(nil)
+	if err != nil {
+		t.Fatalf("Peek after Finish: %v", err)
+	}
+	if head == nil || head.ID != job2.ID {
+		t.Fatalf("expected job2 at the head after job1 finished, got %v", head)
+	}
+
+	pending, err = s.ListPending(nil)
+	if err != nil {
+		t.Fatalf("ListPending after Finish: %v", err)
+	}
+	if len(pending) != 1 {
+		t.Fatalf("expected 1 pending job after Finish, got %d", len(pending))
+	}
+}
+
+func TestMemJobStorePeekEmpty(t *testing.T) {
+	s := newMemJobStore()
+	job, err := s.Peek(nil)
+	if err != nil {
+		t.Fatalf("Peek on empty store: %v", err)
+	}
+	if job != nil {
+		t.Fatalf("expected nil job from an empty store, got %v", job)
+	}
+}
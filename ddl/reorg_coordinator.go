@@ -0,0 +1,353 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ddl
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/big"
+	"sync"
+
+	"github.com/juju/errors"
+	"github.com/pingcap/tidb/kv"
+	"github.com/pingcap/tidb/meta"
+	"github.com/pingcap/tidb/model"
+	log "github.com/sirupsen/logrus"
+)
+
+// reorgChunkCount is the number of key range shards a table's backfill is
+// split into. Each shard is dispatched to a (possibly non-owner) TiDB node
+// as an independent work unit.
+const reorgChunkCount = 16
+
+// reorgChunk is one key range shard of an AddIndex backfill, plus whatever
+// progress has been checkpointed for it so far.
+type reorgChunk struct {
+	startKey   kv.Key
+	endKey     kv.Key
+	checkpoint kv.Key
+	workerAddr string
+	done       bool
+}
+
+// reorgCoordinator is the coordination/bookkeeping layer a horizontally
+// scalable online index build would dispatch work through: it splits the
+// target table's key range into chunks, hands each chunk's key range to a
+// worker node over etcd, tracks per-chunk progress/checkpoints, and only
+// lets the job advance once every chunk reports done.
+//
+// It does not itself perform any index backfill. dispatch only publishes a
+// chunk's key range for a worker to pick up, and pollChunkProgress only reads
+// the done marker a worker writes back; no worker-side handler that scans a
+// range and writes index entries exists anywhere in this tree.
+// onCreateIndexDistributed runs the whole backfill itself, single-node,
+// through onCreateIndex in the same call that observes every chunk done, so
+// jobs routed through this coordinator are not faster than the owner-local
+// path.
+//
+// It only takes over AddIndex jobs dispatched through AddIndexJobListKey;
+// every other job type keeps using the owner-local backfill path.
+type reorgCoordinator struct {
+	d *ddl
+
+	mu        sync.Mutex
+	chunks    map[int64][]*reorgChunk // job ID -> its chunks
+	dedicated map[int64]struct{}      // job ID -> dispatched through AddIndexJobListKey
+}
+
+func newReorgCoordinator(d *ddl) *reorgCoordinator {
+	return &reorgCoordinator{
+		d:         d,
+		chunks:    make(map[int64][]*reorgChunk),
+		dedicated: make(map[int64]struct{}),
+	}
+}
+
+// MarkDedicated records that job was dispatched through the adding-index
+// queue, so runDDLJob knows to route its StateWriteReorganization phase
+// through onCreateIndexDistributed instead of the regular owner-local
+// backfill. Called from getDedicatedAddIndexJob once the owner picks job up
+// off meta.AddIndexJobListKey, which is where addDDLJob puts every
+// ActionAddIndex job.
+func (rc *reorgCoordinator) MarkDedicated(jobID int64) {
+	rc.mu.Lock()
+	rc.dedicated[jobID] = struct{}{}
+	rc.mu.Unlock()
+}
+
+// IsDedicated reports whether job was dispatched through the adding-index
+// queue and should therefore have its backfill sharded across workers.
+func (rc *reorgCoordinator) IsDedicated(jobID int64) bool {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	_, ok := rc.dedicated[jobID]
+	return ok
+}
+
+// splitTableKeyRange splits [startKey, endKey) into n roughly equal byte
+// range shards. This is a coarse approximation until real region boundaries
+// from the PD region cache are wired in; it's good enough to fan a backfill
+// out across several workers without any one shard being wildly larger than
+// the rest.
+//
+// TODO: use kv.Storage's region cache to split on actual region boundaries,
+// so a chunk never straddles a region and each worker's scan stays local.
+func splitTableKeyRange(startKey, endKey kv.Key, n int) []kv.KeyRange {
+	if n <= 1 || len(startKey) == 0 || len(endKey) == 0 {
+		return []kv.KeyRange{{StartKey: startKey, EndKey: endKey}}
+	}
+
+	start := append([]byte(nil), startKey...)
+	end := append([]byte(nil), endKey...)
+	width := len(end)
+	if len(start) > width {
+		width = len(start)
+	}
+	startPadded := make([]byte, width)
+	endPadded := make([]byte, width)
+	copy(startPadded, start)
+	copy(endPadded, end)
+
+	ranges := make([]kv.KeyRange, 0, n)
+	prev := kv.Key(startPadded)
+	for i := 1; i < n; i++ {
+		cut := interpolateKey(startPadded, endPadded, i, n)
+		ranges = append(ranges, kv.KeyRange{StartKey: prev, EndKey: cut})
+		prev = cut
+	}
+	ranges = append(ranges, kv.KeyRange{StartKey: prev, EndKey: kv.Key(endPadded)})
+	return ranges
+}
+
+// interpolateKey returns the key that is i/n of the way from start to end,
+// treating both as big-endian integers of the same width. It's computed as a
+// single multi-byte integer via math/big rather than per-byte: interpolating
+// byte-by-byte without first borrowing across byte boundaries isn't
+// monotonic whenever a byte of end is smaller than the corresponding byte of
+// start (e.g. any range crossing a rollover like a tableID+1 boundary),
+// which can return cuts out of order and split a table into overlapping or
+// backwards chunks.
+func interpolateKey(start, end []byte, i, n int) []byte {
+	startInt := new(big.Int).SetBytes(start)
+	endInt := new(big.Int).SetBytes(end)
+
+	diff := new(big.Int).Sub(endInt, startInt)
+	step := diff.Mul(diff, big.NewInt(int64(i)))
+	step.Quo(step, big.NewInt(int64(n)))
+
+	cut := step.Add(step, startInt)
+
+	out := make([]byte, len(start))
+	b := cut.Bytes()
+	copy(out[len(out)-len(b):], b)
+	return out
+}
+
+// assignChunks splits the table's key range and records one chunk per shard
+// for the job, ready to be handed out to worker nodes.
+func (rc *reorgCoordinator) assignChunks(job *model.Job, startKey, endKey kv.Key) []*reorgChunk {
+	ranges := splitTableKeyRange(startKey, endKey, reorgChunkCount)
+	chunks := make([]*reorgChunk, 0, len(ranges))
+	for _, r := range ranges {
+		chunks = append(chunks, &reorgChunk{startKey: r.StartKey, endKey: r.EndKey, checkpoint: r.StartKey})
+	}
+
+	rc.mu.Lock()
+	rc.chunks[job.ID] = chunks
+	rc.mu.Unlock()
+	return chunks
+}
+
+// dispatch publishes a chunk's work unit under etcd so that any TiDB node
+// watching the reorg dispatch path can pick it up and run the backfill for
+// that key range, reporting checkpoints back under the same path.
+func (rc *reorgCoordinator) dispatch(job *model.Job, idx int, chunk *reorgChunk) error {
+	client := extFor(rc.d).etcdClient
+	if client == nil {
+		// No etcd client wired up (e.g. single-node test setup): there's no
+		// worker to report a checkpoint back to, so there's nothing this
+		// chunk is waiting on either. Mark it done immediately instead of
+		// leaving allChunksDone stuck forever with no way to learn progress.
+		rc.mu.Lock()
+		chunk.done = true
+		rc.mu.Unlock()
+		return nil
+	}
+	key := reorgChunkEtcdKey(job.ID, idx)
+	log.Infof("[ddl] dispatch reorg chunk %s [%s, %s)", key, chunk.startKey, chunk.endKey)
+	return errors.Trace(client.PutKV(key, chunk.startKey.String()))
+}
+
+func reorgChunkEtcdKey(jobID int64, idx int) string {
+	return fmt.Sprintf("/tidb/ddl/reorg/%d/chunk/%d", jobID, idx)
+}
+
+// reorgChunkDoneEtcdKey is where a worker node PUTs a non-empty marker once
+// it finishes backfilling the chunk at reorgChunkEtcdKey(jobID, idx).
+func reorgChunkDoneEtcdKey(jobID int64, idx int) string {
+	return reorgChunkEtcdKey(jobID, idx) + "/done"
+}
+
+// pollChunkProgress checks etcd for completion markers on every chunk of
+// job's backfill that isn't already known to be done, and updates rc.chunks
+// accordingly. This is the other half of dispatch: dispatch hands a chunk's
+// key range to whichever worker picks it up, and this is how the owner
+// learns the worker finished it. Polling (rather than a watch) keeps this in
+// step with the rest of the package, which doesn't otherwise depend on a
+// long-lived etcd watch connection; it costs one GetKV per outstanding chunk
+// per tick, which is cheap relative to reorgChunkCount.
+func (rc *reorgCoordinator) pollChunkProgress(job *model.Job) error {
+	client := extFor(rc.d).etcdClient
+	if client == nil {
+		// No etcd client means dispatch already marked every chunk done
+		// itself; nothing to poll.
+		return nil
+	}
+
+	rc.mu.Lock()
+	chunks := rc.chunks[job.ID]
+	rc.mu.Unlock()
+
+	for idx, chunk := range chunks {
+		rc.mu.Lock()
+		done := chunk.done
+		rc.mu.Unlock()
+		if done {
+			continue
+		}
+		marker, err := client.GetKV(reorgChunkDoneEtcdKey(job.ID, idx))
+		if err != nil {
+			return errors.Trace(err)
+		}
+		if marker == "" {
+			continue
+		}
+		rc.mu.Lock()
+		chunk.done = true
+		rc.mu.Unlock()
+	}
+	return nil
+}
+
+// saveChunkCheckpoint records how far a chunk's backfill has progressed. This
+// is in-process only, like the rest of rc.chunks: if the owner restarts
+// before a chunk finishes, that chunk's checkpoint is lost along with the
+// rest of rc.chunks and the job re-splits its key range from scratch next
+// time it's picked up, the same tradeoff allChunksDone already makes.
+func (rc *reorgCoordinator) saveChunkCheckpoint(job *model.Job, idx int, checkpoint kv.Key) {
+	rc.mu.Lock()
+	if chunks := rc.chunks[job.ID]; idx < len(chunks) {
+		chunks[idx].checkpoint = checkpoint
+	}
+	rc.mu.Unlock()
+}
+
+// allChunksDone reports whether every chunk of a job's backfill has finished,
+// meaning the index can be flipped to StatePublic.
+func (rc *reorgCoordinator) allChunksDone(job *model.Job) bool {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	chunks, ok := rc.chunks[job.ID]
+	if !ok {
+		return false
+	}
+	for _, c := range chunks {
+		if !c.done {
+			return false
+		}
+	}
+	return true
+}
+
+// clear drops the in-memory chunk bookkeeping for a job once it's finished.
+func (rc *reorgCoordinator) clear(job *model.Job) {
+	rc.mu.Lock()
+	delete(rc.chunks, job.ID)
+	rc.mu.Unlock()
+}
+
+// hasChunks reports whether a job's key range has already been split and
+// assigned.
+func (rc *reorgCoordinator) hasChunks(job *model.Job) bool {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	_, ok := rc.chunks[job.ID]
+	return ok
+}
+
+// tableKeyRange returns an approximate [start, end) key range covering a
+// table's records, used only to decide where to cut backfill chunks.
+//
+// TODO: share this with the real record key encoding in tablecodec once the
+// reorg coordinator moves out of its experimental stage.
+func tableKeyRange(tableID int64) (kv.Key, kv.Key) {
+	buf := make([]byte, 8)
+	binary.BigEndian.PutUint64(buf, uint64(tableID))
+	start := append([]byte{'t'}, buf...)
+	binary.BigEndian.PutUint64(buf, uint64(tableID+1))
+	end := append([]byte{'t'}, buf...)
+	return start, end
+}
+
+// onCreateIndexDistributed runs the StateWriteReorganization phase of an
+// AddIndex job that was dispatched through the adding-index queue. With an
+// etcd client wired up, it splits the table's key range into chunks on first
+// entry, fans the key ranges out to worker nodes via etcd, and waits until
+// every chunk reports done before running the actual backfill through the
+// regular owner-local d.onCreateIndex.
+//
+// No etcd client has been wired into any *ddl in this tree yet, and no
+// worker-side handler exists anywhere (it isn't DDL-owner code, so it
+// wouldn't live in this package even once it does exist) -- see
+// reorgCoordinator's doc comment. So today this is unimplemented scaffolding,
+// not a working feature: rather than pay for a 16-way key split and dispatch
+// that has nothing to wait on, this skips straight to the single-node path
+// whenever extFor(d).etcdClient is nil, which is every call in this tree
+// right now. All other schema states are handled exactly like a regular
+// AddIndex job.
+func (d *ddl) onCreateIndexDistributed(t *meta.Meta, job *model.Job) (ver int64, err error) {
+	rc := extFor(d).reorgCoordinator
+	if job.SchemaState != model.StateWriteReorganization {
+		return d.onCreateIndex(t, job)
+	}
+
+	if extFor(d).etcdClient == nil {
+		log.Warnf("[ddl] job %d: distributed backfill is unimplemented scaffolding without an etcd client wired up; running the single-node backfill instead", job.ID)
+		return d.onCreateIndex(t, job)
+	}
+
+	if !rc.hasChunks(job) {
+		startKey, endKey := tableKeyRange(job.TableID)
+		chunks := rc.assignChunks(job, startKey, endKey)
+		for idx, chunk := range chunks {
+			if dispatchErr := rc.dispatch(job, idx, chunk); dispatchErr != nil {
+				return 0, errors.Trace(dispatchErr)
+			}
+		}
+		log.Infof("[ddl] job %d backfill split into %d chunks", job.ID, len(chunks))
+	}
+
+	if err = rc.pollChunkProgress(job); err != nil {
+		return 0, errors.Trace(err)
+	}
+
+	if !rc.allChunksDone(job) {
+		// Not finished yet; come back on the next tick. The job stays in
+		// StateWriteReorganization, which is a valid place to be re-entered.
+		return 0, nil
+	}
+
+	rc.clear(job)
+	return d.onCreateIndex(t, job)
+}
@@ -0,0 +1,62 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ddl
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestInterpolateKeyMonotonic(t *testing.T) {
+	// Regression case: end's high byte is only 1 more than start's, so the
+	// low byte rolls over (0xF0 -> 0x10), which a per-byte interpolation
+	// without cross-byte borrowing gets backwards.
+	start := []byte{0x10, 0xF0}
+	end := []byte{0x11, 0x10}
+	n := 8
+
+	prev := start
+	for i := 1; i <= n; i++ {
+		cut := interpolateKey(start, end, i, n)
+		if bytes.Compare(cut, prev) < 0 {
+			t.Fatalf("cut %d (%x) is before previous cut (%x); interpolation isn't monotonic", i, cut, prev)
+		}
+		prev = cut
+	}
+	if !bytes.Equal(prev, end) {
+		t.Fatalf("final cut %x should equal end %x", prev, end)
+	}
+}
+
+func TestSplitTableKeyRangeMonotonic(t *testing.T) {
+	startKey, endKey := tableKeyRange(1)
+	ranges := splitTableKeyRange(startKey, endKey, reorgChunkCount)
+	if len(ranges) != reorgChunkCount {
+		t.Fatalf("expected %d ranges, got %d", reorgChunkCount, len(ranges))
+	}
+	for i, r := range ranges {
+		if bytes.Compare(r.StartKey, r.EndKey) > 0 {
+			t.Fatalf("range %d has StartKey %x after EndKey %x", i, r.StartKey, r.EndKey)
+		}
+		if i > 0 && !bytes.Equal(r.StartKey, ranges[i-1].EndKey) {
+			t.Fatalf("range %d StartKey %x doesn't chain from range %d EndKey %x", i, r.StartKey, i-1, ranges[i-1].EndKey)
+		}
+	}
+	if !bytes.Equal([]byte(ranges[0].StartKey), []byte(startKey)) {
+		t.Fatalf("first range should start at %x, got %x", startKey, ranges[0].StartKey)
+	}
+	if !bytes.Equal([]byte(ranges[len(ranges)-1].EndKey), []byte(endKey)) {
+		t.Fatalf("last range should end at %x, got %x", endKey, ranges[len(ranges)-1].EndKey)
+	}
+}
@@ -0,0 +1,106 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ddl
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/pingcap/tidb/metrics"
+	"github.com/pingcap/tidb/terror"
+)
+
+// errSchemaLeaseExpired is returned by schemaLeaseGuard.Check when this node
+// has gone longer than the schema lease without successfully reloading
+// schema. It's retryable: once the node catches up (or a newer node takes
+// over), the same statement will succeed.
+var errSchemaLeaseExpired = terror.ClassDDL.New(terror.CodeUnknown, "schema lease expired, this node can't guarantee its schema is no more than one version stale")
+
+// schemaLeaseGuard tracks the F1 online schema change algorithm's lease
+// invariant: at any moment at most two schema versions may be in effect,
+// which only holds if every node that falls behind on reloading schema stops
+// serving before its lease runs out. Check fails with errSchemaLeaseExpired
+// once more than lease has passed since the last MarkReloaded.
+//
+// In this package MarkReloaded is only called by the DDL owner, when
+// waitSchemaChanged sees every node on the latest version, and Check is only
+// called by addDDLJob and CheckSchemaLease. SQL execution paths don't consult
+// the guard, so lagging nodes don't fence themselves, and the owner still
+// waits 2*lease in handleDDLJobQueue rather than lease.
+type schemaLeaseGuard struct {
+	lease      time.Duration
+	lastReload atomic.Value // time.Time
+	reloaded   int32        // atomically set once the first MarkReloaded lands
+
+	// now is overridable so tests can inject clock skew without sleeping.
+	now func() time.Time
+}
+
+func newSchemaLeaseGuard(lease time.Duration) *schemaLeaseGuard {
+	return &schemaLeaseGuard{lease: lease, now: time.Now}
+}
+
+// setNowFunc overrides the guard's clock, letting tests simulate a node that
+// has fallen behind without an actual sleep.
+func (g *schemaLeaseGuard) setNowFunc(now func() time.Time) {
+	g.now = now
+}
+
+// MarkReloaded records that this node just successfully reloaded schema,
+// resetting the lease clock. Check is a no-op until the first call to
+// MarkReloaded: a node that has never confirmed a reload hasn't necessarily
+// fallen behind, it just hasn't been told yet, so there's nothing to fence.
+func (g *schemaLeaseGuard) MarkReloaded(at time.Time) {
+	g.lastReload.Store(at)
+	atomic.StoreInt32(&g.reloaded, 1)
+}
+
+// Check returns errSchemaLeaseExpired if this node has gone longer than the
+// lease since its last successful schema reload. Callers on every SQL
+// execution path must treat this as fatal to the in-flight statement: stop
+// immediately and don't start a new transaction until a reload succeeds.
+func (g *schemaLeaseGuard) Check() error {
+	if g.lease == 0 {
+		// Lease disabled (e.g. some test setups); nothing to enforce.
+		return nil
+	}
+	if atomic.LoadInt32(&g.reloaded) == 0 {
+		// No reload has ever been reported yet, so there's no lease clock to
+		// have expired; MarkReloaded hasn't been wired into every reload path
+		// in this tree yet (see its callers), so failing fast here would
+		// permanently block DDL submission instead of just until the first
+		// reload.
+		return nil
+	}
+	last := g.lastReload.Load().(time.Time)
+	if g.now().Sub(last) > g.lease {
+		metrics.SchemaLeaseErrorCounter.Inc()
+		return errSchemaLeaseExpired
+	}
+	return nil
+}
+
+// CheckSchemaLease reports whether d's node can still guarantee its schema
+// is no more than one version stale; it returns errSchemaLeaseExpired if the
+// lease has lapsed. It's exported for SQL execution paths to call before
+// starting a new transaction, but session/executor, where those paths live,
+// aren't part of this package (or this tree), so nothing outside ddl calls
+// it. DDL submission goes through the equivalent check in addDDLJob.
+func (d *ddl) CheckSchemaLease() error {
+	guard := extFor(d).leaseGuard
+	if guard == nil {
+		return nil
+	}
+	return guard.Check()
+}
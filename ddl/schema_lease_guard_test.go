@@ -0,0 +1,98 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ddl
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSchemaLeaseGuardNoReloadYet(t *testing.T) {
+	g := newSchemaLeaseGuard(time.Second)
+	now := time.Now()
+	g.setNowFunc(func() time.Time { return now.Add(time.Hour) })
+	if err := g.Check(); err != nil {
+		t.Fatalf("Check should be a no-op before the first MarkReloaded, got %v", err)
+	}
+}
+
+func TestSchemaLeaseGuardExpiresAfterLease(t *testing.T) {
+	g := newSchemaLeaseGuard(time.Second)
+	now := time.Now()
+	g.setNowFunc(func() time.Time { return now })
+	g.MarkReloaded(now)
+
+	if err := g.Check(); err != nil {
+		t.Fatalf("Check should pass right after MarkReloaded, got %v", err)
+	}
+
+	g.setNowFunc(func() time.Time { return now.Add(2 * time.Second) })
+	if err := g.Check(); !errSchemaLeaseExpired.Equal(err) {
+		t.Fatalf("Check should report the lease expired, got %v", err)
+	}
+}
+
+func TestSchemaLeaseGuardRecoversAfterReload(t *testing.T) {
+	g := newSchemaLeaseGuard(time.Second)
+	now := time.Now()
+	g.setNowFunc(func() time.Time { return now })
+	g.MarkReloaded(now)
+	g.setNowFunc(func() time.Time { return now.Add(2 * time.Second) })
+	if err := g.Check(); !errSchemaLeaseExpired.Equal(err) {
+		t.Fatalf("expected the guard to have fenced itself off, got %v", err)
+	}
+
+	reloadedAt := now.Add(2 * time.Second)
+	g.MarkReloaded(reloadedAt)
+	g.setNowFunc(func() time.Time { return reloadedAt })
+	if err := g.Check(); err != nil {
+		t.Fatalf("Check should pass again right after a fresh reload, got %v", err)
+	}
+}
+
+func TestSchemaLeaseGuardDisabled(t *testing.T) {
+	g := newSchemaLeaseGuard(0)
+	g.setNowFunc(func() time.Time { return time.Now().Add(time.Hour) })
+	if err := g.Check(); err != nil {
+		t.Fatalf("a zero lease should disable the guard entirely, got %v", err)
+	}
+}
+
+func TestDDLCheckSchemaLease(t *testing.T) {
+	var d *ddl
+	// extFor(d) stores state in the package-global extByDDL side table keyed
+	// on d, which here is the literal nil *ddl; release it once this test is
+	// done so it can't leak into any other test or caller that also does
+	// extFor(nil).
+	t.Cleanup(func() { releaseExt(d) })
+
+	if err := d.CheckSchemaLease(); err != nil {
+		t.Fatalf("CheckSchemaLease with no guard wired up should be a no-op, got %v", err)
+	}
+
+	guard := newSchemaLeaseGuard(time.Second)
+	now := time.Now()
+	guard.setNowFunc(func() time.Time { return now })
+	guard.MarkReloaded(now)
+	extFor(d).leaseGuard = guard
+
+	if err := d.CheckSchemaLease(); err != nil {
+		t.Fatalf("CheckSchemaLease should pass right after MarkReloaded, got %v", err)
+	}
+
+	guard.setNowFunc(func() time.Time { return now.Add(2 * time.Second) })
+	if err := d.CheckSchemaLease(); !errSchemaLeaseExpired.Equal(err) {
+		t.Fatalf("CheckSchemaLease should report the lease expired, got %v", err)
+	}
+}